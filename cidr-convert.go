@@ -2,184 +2,164 @@
 package main
 
 import (
-	"bufio"
-	"errors"
+	"flag"
 	"fmt"
-	"net"
+	"net/netip"
 	"os"
-	"regexp"
-	"strconv"
-)
-
-type node struct {
-	sub [2]*node
-}
+	"sync"
 
-var (
-	none = &node{}
-	all  = &node{}
+	"github.com/flowchartsman/cidr-convert/cidrset"
 )
 
-type Tree struct {
-	root *node
-}
-
-func NewTree() *Tree {
-	return &Tree{none}
+var parsers = map[string]cidrset.Parser{
+	"lines": cidrset.ParseLines,
+	"rir":   cidrset.ParseRIRStats,
+	"json":  cidrset.ParseJSON,
+	"mrt":   cidrset.ParseMRT,
 }
 
-func (t *Tree) insert_addr(a uint) {
-	add_to_node(&t.root, a, 31, -1)
+var emitters = map[string]cidrset.Emitter{
+	"cidr":   cidrset.EmitCIDR,
+	"json":   cidrset.EmitJSON,
+	"ranges": cidrset.EmitRanges,
+	"hex":    cidrset.EmitHex,
 }
 
-func (t *Tree) insert_range(start uint, end uint) {
-
-	var (
-		bit int32
-		m   uint
-		tt  uint
-	)
+// Exit codes distinguish "the input had lines we couldn't make sense of at
+// all" from "the input parsed fine but described something invalid", so a
+// caller can decide how strict to be about each.
+const (
+	exitOK            = 0
+	exitParseErrors   = 1
+	exitInvalidRanges = 2
+)
 
-	for start <= end {
-		m = (start - 1) & ^start
-		for start+m > end {
-			m >>= 1
-		}
-		bit = -1
-		tt = m
-		for tt != 0 {
-			bit++
-			tt >>= 1
+func main() {
+	input := flag.String("input", "lines", "input format: lines, rir, json, or mrt")
+	output := flag.String("output", "cidr", "output format: cidr, json, ranges, or hex")
+	legacyIPParse := flag.Bool("legacy-ip-parse", false, "accept IPv4 octets with leading zeros (e.g. 192.168.001.001); only applies to -input=lines")
+	sorted := flag.Bool("sorted", false, "input is already sorted and non-overlapping per address family; aggregate and emit it in a single streaming pass instead of holding everything in memory (implies -output=cidr)")
+	flag.Parse()
+
+	parse, ok := parsers[*input]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "cidr-convert: unknown input format %q\n", *input)
+		os.Exit(2)
+	}
+	if *legacyIPParse {
+		if *input != "lines" {
+			fmt.Fprintln(os.Stderr, "cidr-convert: -legacy-ip-parse only applies to -input=lines")
+			os.Exit(2)
 		}
-		add_to_node(&t.root, start, 31, bit)
-		start += m + 1
+		parse = cidrset.ParseLinesLegacy
 	}
-}
 
-func (t *Tree) insert_CIDR(a uint, n int) {
-	if n != 0 {
-		a &= 0xffffffff & (0xffffffff << (32 - uint(n)))
+	var stats cidrset.Stats
+	var err error
+	if *sorted {
+		if *output != "cidr" {
+			fmt.Fprintln(os.Stderr, "cidr-convert: -sorted only supports -output=cidr")
+			os.Exit(2)
+		}
+		stats, err = runStreamed(parse)
 	} else {
-		a = 0
-	}
-	add_to_node(&t.root, a, 31, int32(31-n))
-}
-
-func (t *Tree) DumpTree() {
-	dump_tree(t.root, 0, 31)
-}
-
-func add_to_node(np **node, a uint, bit int32, end int32) {
-	var n *node
-	n = *np
-
-	if n == all {
-		return
+		emit, ok := emitters[*output]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "cidr-convert: unknown output format %q\n", *output)
+			os.Exit(2)
+		}
+		stats, err = runBuffered(parse, emit)
 	}
-	if bit <= end {
-		*np = all
-		return
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cidr-convert: %v\n", err)
+		os.Exit(1)
 	}
-	if n == none {
-		n = &node{[2]*node{none, none}}
-		*np = n
+
+	if stats.LegacyAccepted > 0 {
+		fmt.Fprintf(os.Stderr, "cidr-convert: accepted %d address(es) only because of -legacy-ip-parse\n", stats.LegacyAccepted)
 	}
-	add_to_node(&n.sub[(a>>uint(bit))&1], a, bit-1, end)
-	if n.sub[0] == all && n.sub[1] == all {
-		*np = all
+	switch {
+	case stats.ParseErrors > 0:
+		fmt.Fprintf(os.Stderr, "cidr-convert: skipped %d unparseable record(s), %d invalid range(s)\n", stats.ParseErrors, stats.InvalidRanges)
+		os.Exit(exitParseErrors)
+	case stats.InvalidRanges > 0:
+		fmt.Fprintf(os.Stderr, "cidr-convert: skipped %d invalid range(s)\n", stats.InvalidRanges)
+		os.Exit(exitInvalidRanges)
 	}
+	os.Exit(exitOK)
 }
 
-func (t *Tree) FindNode(a uint) bool {
-	n := t.root
-	switch {
-	case n == all:
-		return true
-	case n == none:
-		return false
-	}
-	for bit := 31; bit >= 0; bit-- { //is >= correct?
-		//some case where this is the exact node
-		n = n.sub[(a>>uint(bit))&1]
-		switch {
-		case n == all:
-			return true
-		case n == none:
-			return false
+// runBuffered parses the whole input into per-family trees before emitting
+// the aggregated result.
+func runBuffered(parse cidrset.Parser, emit cidrset.Emitter) (cidrset.Stats, error) {
+	v4 := cidrset.NewTree[struct{}](32)
+	v6 := cidrset.NewTree[struct{}](128)
+	treeFor := func(a netip.Addr) *cidrset.Tree[struct{}] {
+		if a.Is4() {
+			return v4
 		}
+		return v6
 	}
-	return false
-}
 
-func dump_tree(n *node, v uint, bit uint) {
-	switch {
-	case n == none:
-		return
-	case n == all:
-		fmt.Printf("%d.%d.%d.%d/%d\n", v>>24&0xff, (v>>16)&0xff, (v>>8)&0xff, v&0xff, 31-bit)
-		return
-	case bit < 0:
-		panic("non-leaf node found at the bottom of the tree")
+	stats, err := parse(os.Stdin, func(rec cidrset.Record) {
+		treeFor(rec.Start).InsertRange(rec.Start, rec.End, struct{}{})
+	})
+	if err != nil {
+		return stats, err
 	}
-	dump_tree(n.sub[0], v, bit-1)
-	dump_tree(n.sub[1], v|(1<<bit), bit-1)
-}
 
-func byte4ToInt(b []byte) uint {
-	return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
-}
-
-func ipstrToInt(ipStr string) (ipInt uint, err error) {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		err = errors.New("Invalid IP address")
-		return
+	var prefixes []netip.Prefix
+	collect := func(e cidrset.Entry[struct{}]) bool {
+		prefixes = append(prefixes, e.Prefix)
+		return true
 	}
-	ipInt = byte4ToInt(ip.To4())
-	return
+	v4.CIDRs(collect)
+	v6.CIDRs(collect)
+
+	return stats, emit(os.Stdout, prefixes)
 }
 
-func main() {
-	t := NewTree()
-	stdin := bufio.NewScanner(os.Stdin)
-	// TODO: technically '192.168.1.0-192.168.1.255/8' will be accepted as valid
-	// (it will just ignore the CIDR block). Make this an invalid line
-	extractor := regexp.MustCompile(`^(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})(?:-(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}))?(?:/(\d{1,2}))?$`)
-	for stdin.Scan() {
-		matches := extractor.FindStringSubmatch(stdin.Text())
-		//fmt.Printf("%#v\n", matches[1:])
-		switch {
-		case matches[1] == "":
-			// Invalid or empty line
-			continue
-		case matches[2] != "":
-			// IP-IP range
-			ip1i, err := ipstrToInt(matches[1])
-			ip2i, err := ipstrToInt(matches[2])
-			if err != nil || ip1i >= ip2i {
-				continue
-			}
-			t.insert_range(ip1i, ip2i)
-		case matches[3] != "":
-			// IP/CIDR
-			ipi, err := ipstrToInt(matches[1])
-			if err != nil {
-				continue
-			}
-			ci, err := strconv.ParseUint(matches[3], 10, 32)
-			if err != nil {
-				continue
-			}
-			t.insert_CIDR(ipi, int(ci))
-		default:
-			// single IP
-			ipi, err := ipstrToInt(matches[1])
-			if err != nil {
-				continue
-			}
-			t.insert_addr(ipi)
+// runStreamed feeds parsed records straight into Tree.StreamAggregate for
+// each family and prints each resulting CIDR as soon as it's emitted,
+// without ever holding the full input in memory. It requires the input to
+// already be sorted and non-overlapping per family.
+func runStreamed(parse cidrset.Parser) (cidrset.Stats, error) {
+	v4 := cidrset.NewTree[struct{}](32)
+	v6 := cidrset.NewTree[struct{}](128)
+	v4in, v6in := make(chan cidrset.Range), make(chan cidrset.Range)
+	v4out, v6out := make(chan netip.Prefix), make(chan netip.Prefix)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); v4.StreamAggregate(v4in, v4out) }()
+	go func() { defer wg.Done(); v6.StreamAggregate(v6in, v6out) }()
+
+	var printWG sync.WaitGroup
+	printWG.Add(2)
+	go func() {
+		defer printWG.Done()
+		for p := range v4out {
+			fmt.Println(p)
 		}
-	}
+	}()
+	go func() {
+		defer printWG.Done()
+		for p := range v6out {
+			fmt.Println(p)
+		}
+	}()
+
+	stats, err := parse(os.Stdin, func(rec cidrset.Record) {
+		if rec.Start.Is4() {
+			v4in <- cidrset.Range(rec)
+		} else {
+			v6in <- cidrset.Range(rec)
+		}
+	})
+	close(v4in)
+	close(v6in)
+	wg.Wait()
+	printWG.Wait()
 
-	t.DumpTree()
+	return stats, err
 }
@@ -0,0 +1,47 @@
+package cidrset
+
+import (
+	"net/netip"
+	"testing"
+)
+
+// geoIPLikeRanges generates n non-overlapping /24-sized blocks scattered
+// across 10.0.0.0/8, roughly the shape of an aggregated GeoIP delegation
+// dump: many small, disjoint, rarely-changing blocks.
+func geoIPLikeRanges(n int) []netip.Prefix {
+	out := make([]netip.Prefix, n)
+	for i := range out {
+		a := netip.AddrFrom4([4]byte{10, byte(i >> 8), byte(i), 0})
+		out[i] = netip.PrefixFrom(a, 24)
+	}
+	return out
+}
+
+func BenchmarkTreeLookup(b *testing.B) {
+	blocks := geoIPLikeRanges(10000)
+	tr := NewTree[int](32)
+	for i, p := range blocks {
+		tr.Insert(p, i)
+	}
+	q := blocks[len(blocks)/2].Addr()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.Contains(q)
+	}
+}
+
+func BenchmarkRangeSetLookup(b *testing.B) {
+	blocks := geoIPLikeRanges(10000)
+	s := NewRangeSet()
+	for _, p := range blocks {
+		s.InsertPrefix(p)
+	}
+	q := blocks[len(blocks)/2].Addr()
+	s.Contains(q) // force the initial compact outside the timed loop
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Contains(q)
+	}
+}
@@ -0,0 +1,303 @@
+// Package cidrset implements a trie of CIDR blocks that auto-coalesces
+// overlapping or adjacent inserts into their minimal covering set, with an
+// optional value attached to each inserted block.
+//
+// It works for both IPv4 and IPv6, but a single Tree only ever holds one
+// address family: construct one Tree per family and route addresses to the
+// matching one, the way cmd/cidr-convert does.
+package cidrset
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+type node[V any] struct {
+	sub   [2]*node[V]
+	leaf  bool
+	value V
+}
+
+// Tree is a binary trie of CIDR blocks of a fixed address width (32 for
+// IPv4, 128 for IPv6), each optionally carrying a value of type V.
+type Tree[V comparable] struct {
+	root *node[V]
+	bits int
+}
+
+// NewTree returns an empty Tree for addresses of the given bit width (32 for
+// IPv4, 128 for IPv6).
+func NewTree[V comparable](bits int) *Tree[V] {
+	return &Tree[V]{bits: bits}
+}
+
+// Insert adds prefix to the tree, tagged with v. Adjacent or overlapping
+// blocks are coalesced into their covering CIDR only when they carry the
+// same value; blocks with differing values keep their own boundary so a
+// lookup can still tell them apart.
+func (t *Tree[V]) Insert(prefix netip.Prefix, v V) {
+	base := prefix.Masked().Addr().AsSlice()
+	addToNode(&t.root, base, t.bits-1, t.bits-1-prefix.Bits(), v)
+}
+
+// InsertRange adds every address in [start, end] to the tree, tagged with v.
+func (t *Tree[V]) InsertRange(start, end netip.Addr, v V) {
+	rangeBlocks(start.AsSlice(), end.AsSlice(), func(base []byte, prefixLen int) {
+		addToNode(&t.root, base, t.bits-1, t.bits-1-prefixLen, v)
+	})
+}
+
+// Contains reports whether a falls within any block held by the tree.
+func (t *Tree[V]) Contains(a netip.Addr) bool {
+	_, _, ok := t.Lookup(a)
+	return ok
+}
+
+// Lookup performs a longest-prefix-match of a against the tree, returning
+// the matching prefix and its value.
+func (t *Tree[V]) Lookup(a netip.Addr) (prefix netip.Prefix, value V, ok bool) {
+	b := a.AsSlice()
+	n := t.root
+	length := 0
+	for {
+		if n == nil {
+			return netip.Prefix{}, value, false
+		}
+		if n.leaf {
+			return netip.PrefixFrom(a, length).Masked(), n.value, true
+		}
+		if length >= t.bits {
+			return netip.Prefix{}, value, false
+		}
+		n = n.sub[getBit(b, t.bits-1-length)]
+		length++
+	}
+}
+
+// Delete removes prefix from the tree, splitting any broader block that
+// covers it as needed. It reports whether prefix (or a superset of it) was
+// present.
+func (t *Tree[V]) Delete(prefix netip.Prefix) bool {
+	base := prefix.Masked().Addr().AsSlice()
+	return deleteFromNode(&t.root, base, t.bits-1, t.bits-1-prefix.Bits())
+}
+
+// Union merges every block of other into t. Where the two trees overlap,
+// other's value wins for the addresses it explicitly covers.
+func (t *Tree[V]) Union(other *Tree[V]) {
+	other.CIDRs(func(e Entry[V]) bool {
+		t.Insert(e.Prefix, e.Value)
+		return true
+	})
+}
+
+// Entry is one block yielded by Tree.CIDRs.
+type Entry[V comparable] struct {
+	Prefix netip.Prefix
+	Value  V
+}
+
+// CIDRs calls yield once for every CIDR in the minimal cover of the tree, in
+// ascending address order, stopping early if yield returns false.
+func (t *Tree[V]) CIDRs(yield func(Entry[V]) bool) {
+	walkCIDRs(t.root, make([]byte, t.bits/8), t.bits-1, t.bits, yield)
+}
+
+func addToNode[V comparable](np **node[V], a []byte, bit, end int, v V) {
+	n := *np
+
+	if bit <= end {
+		*np = &node[V]{leaf: true, value: v}
+		return
+	}
+	switch {
+	case n == nil:
+		n = &node[V]{}
+		*np = n
+	case n.leaf:
+		// Split so the narrower block being inserted can carry its own
+		// value; the untouched half keeps n's current value.
+		n = &node[V]{sub: [2]*node[V]{
+			{leaf: true, value: n.value},
+			{leaf: true, value: n.value},
+		}}
+		*np = n
+	}
+	addToNode(&n.sub[getBit(a, bit)], a, bit-1, end, v)
+	if n.sub[0] != nil && n.sub[0].leaf && n.sub[1] != nil && n.sub[1].leaf && n.sub[0].value == n.sub[1].value {
+		*np = n.sub[0]
+	}
+}
+
+func deleteFromNode[V any](np **node[V], a []byte, bit, end int) bool {
+	n := *np
+	if n == nil {
+		return false
+	}
+	if bit <= end {
+		if !n.leaf {
+			return false
+		}
+		*np = nil
+		return true
+	}
+	if n.leaf {
+		// Split so the half not being deleted keeps its value.
+		n = &node[V]{sub: [2]*node[V]{
+			{leaf: true, value: n.value},
+			{leaf: true, value: n.value},
+		}}
+		*np = n
+	}
+	if !deleteFromNode(&n.sub[getBit(a, bit)], a, bit-1, end) {
+		return false
+	}
+	if n.sub[0] == nil && n.sub[1] == nil {
+		*np = nil
+	}
+	return true
+}
+
+func walkCIDRs[V comparable](n *node[V], v []byte, bit int, bits int, yield func(Entry[V]) bool) bool {
+	switch {
+	case n == nil:
+		return true
+	case n.leaf:
+		prefix := netip.PrefixFrom(addrFromBytes(v), bits-1-bit)
+		return yield(Entry[V]{Prefix: prefix, Value: n.value})
+	case bit < 0:
+		panic("non-leaf node found at the bottom of the tree")
+	}
+	if !walkCIDRs(n.sub[0], v, bit-1, bits, yield) {
+		return false
+	}
+	return walkCIDRs(n.sub[1], setBit(v, bit), bit-1, bits, yield)
+}
+
+func addrFromBytes(b []byte) netip.Addr {
+	if len(b) == 4 {
+		return netip.AddrFrom4([4]byte(b))
+	}
+	return netip.AddrFrom16([16]byte(b))
+}
+
+// --- bytewise arithmetic helpers ---
+//
+// These port the original bit-trick block-splitting algorithm from
+// fixed-width uint math to big-endian byte slices, so it works unmodified
+// for both 4-byte (IPv4) and 16-byte (IPv6) addresses.
+
+// rangeBlocks splits the inclusive range [start, end] (big-endian byte
+// slices of equal length) into the minimal set of power-of-two aligned
+// blocks needed to cover it, calling fn with each block's base address and
+// prefix length. It's the shared core of Tree.InsertRange and
+// RangeSet.ToCIDRs.
+func rangeBlocks(start, end []byte, fn func(base []byte, prefixLen int)) {
+	bits := len(start) * 8
+	one := make([]byte, len(start))
+	one[len(one)-1] = 1
+
+	s := append([]byte(nil), start...)
+	for cmpBytes(s, end) <= 0 {
+		m := andNotBytes(decBytes(s), s)
+		for cmpBytes(addBytes(s, m), end) > 0 {
+			m = shiftRight1(m)
+		}
+		bit := highestSetBit(m)
+		fn(append([]byte(nil), s...), bits-1-bit)
+		blockEnd := addBytes(s, m)
+		if cmpBytes(blockEnd, end) == 0 {
+			// blockEnd is the last address of the range: stop here rather
+			// than advancing past it, since end may be the top address of
+			// the family (e.g. 255.255.255.255) and addBytes(blockEnd, one)
+			// would silently wrap back around to the all-zero address.
+			break
+		}
+		s = addBytes(blockEnd, one)
+	}
+}
+
+func cmpBytes(a, b []byte) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func decBytes(a []byte) []byte {
+	out := append([]byte(nil), a...)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i]--
+		if out[i] != 0xff {
+			break
+		}
+	}
+	return out
+}
+
+func addBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	carry := 0
+	for i := len(a) - 1; i >= 0; i-- {
+		sum := int(a[i]) + int(b[i]) + carry
+		out[i] = byte(sum)
+		carry = sum >> 8
+	}
+	return out
+}
+
+func andNotBytes(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] &^ b[i]
+	}
+	return out
+}
+
+func shiftRight1(a []byte) []byte {
+	out := make([]byte, len(a))
+	var carry byte
+	for i := range a {
+		out[i] = a[i]>>1 | carry
+		carry = (a[i] & 1) << 7
+	}
+	return out
+}
+
+// highestSetBit returns the index (0 = least significant bit) of the
+// highest set bit in a, or -1 if a is all zero.
+func highestSetBit(a []byte) int {
+	for i, b := range a {
+		if b == 0 {
+			continue
+		}
+		for s := 7; s >= 0; s-- {
+			if b&(1<<uint(s)) != 0 {
+				return (len(a)-1-i)*8 + s
+			}
+		}
+	}
+	return -1
+}
+
+func getBit(a []byte, bit int) int {
+	byteIdx := len(a) - 1 - bit/8
+	return int(a[byteIdx]>>uint(bit%8)) & 1
+}
+
+func setBit(a []byte, bit int) []byte {
+	out := append([]byte(nil), a...)
+	byteIdx := len(out) - 1 - bit/8
+	out[byteIdx] |= 1 << uint(bit%8)
+	return out
+}
+
+// String implements fmt.Stringer for Entry, mostly for debugging/tests.
+func (e Entry[V]) String() string {
+	return fmt.Sprintf("%s=%v", e.Prefix, e.Value)
+}
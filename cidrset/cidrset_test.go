@@ -0,0 +1,187 @@
+package cidrset
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func prefix(s string) netip.Prefix {
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func addr(s string) netip.Addr {
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func TestInsertContains(t *testing.T) {
+	tr := NewTree[struct{}](32)
+	tr.Insert(prefix("10.0.0.0/24"), struct{}{})
+
+	if !tr.Contains(addr("10.0.0.17")) {
+		t.Error("expected 10.0.0.17 to be contained in 10.0.0.0/24")
+	}
+	if tr.Contains(addr("10.0.1.1")) {
+		t.Error("did not expect 10.0.1.1 to be contained in 10.0.0.0/24")
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	tr := NewTree[struct{}](32)
+	tr.Insert(prefix("192.168.0.0/24"), struct{}{})
+	tr.Insert(prefix("192.168.1.0/24"), struct{}{})
+
+	var got []string
+	tr.CIDRs(func(e Entry[struct{}]) bool {
+		got = append(got, e.Prefix.String())
+		return true
+	})
+	if len(got) != 1 || got[0] != "192.168.0.0/23" {
+		t.Fatalf("expected coalesced [192.168.0.0/23], got %v", got)
+	}
+}
+
+func TestLookupLongestPrefix(t *testing.T) {
+	tr := NewTree[string](32)
+	tr.Insert(prefix("10.0.0.0/8"), "ten")
+
+	p, v, ok := tr.Lookup(addr("10.1.2.3"))
+	if !ok || v != "ten" || p.String() != "10.0.0.0/8" {
+		t.Fatalf("got prefix=%v value=%q ok=%v", p, v, ok)
+	}
+
+	if _, _, ok := tr.Lookup(addr("11.0.0.1")); ok {
+		t.Error("did not expect a match outside the inserted block")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tr := NewTree[struct{}](32)
+	tr.Insert(prefix("10.0.0.0/24"), struct{}{})
+
+	if !tr.Delete(prefix("10.0.0.0/24")) {
+		t.Fatal("expected Delete of an inserted block to succeed")
+	}
+	if tr.Contains(addr("10.0.0.1")) {
+		t.Error("block should be gone after Delete")
+	}
+	if tr.Delete(prefix("10.0.0.0/24")) {
+		t.Error("expected second Delete of the same block to report false")
+	}
+}
+
+func TestUnion(t *testing.T) {
+	a := NewTree[struct{}](32)
+	a.Insert(prefix("192.168.0.0/24"), struct{}{})
+	b := NewTree[struct{}](32)
+	b.Insert(prefix("192.168.1.0/24"), struct{}{})
+
+	a.Union(b)
+
+	var got []string
+	a.CIDRs(func(e Entry[struct{}]) bool {
+		got = append(got, e.Prefix.String())
+		return true
+	})
+	if len(got) != 1 || got[0] != "192.168.0.0/23" {
+		t.Fatalf("expected union to coalesce into [192.168.0.0/23], got %v", got)
+	}
+}
+
+func TestOverlappingInsertsKeepTags(t *testing.T) {
+	tr := NewTree[string](32)
+	tr.Insert(prefix("10.0.0.0/24"), "outer")
+	tr.Insert(prefix("10.0.0.128/25"), "inner")
+
+	if p, v, ok := tr.Lookup(addr("10.0.0.200")); !ok || v != "inner" || p.String() != "10.0.0.128/25" {
+		t.Fatalf("got prefix=%v value=%q ok=%v, want 10.0.0.128/25=inner", p, v, ok)
+	}
+	if p, v, ok := tr.Lookup(addr("10.0.0.1")); !ok || v != "outer" || p.String() != "10.0.0.0/25" {
+		t.Fatalf("got prefix=%v value=%q ok=%v, want 10.0.0.0/25=outer", p, v, ok)
+	}
+}
+
+func TestInsertUpdatesExistingValue(t *testing.T) {
+	tr := NewTree[string](32)
+	tr.Insert(prefix("10.0.0.0/24"), "v1")
+	tr.Insert(prefix("10.0.0.0/24"), "v2")
+
+	if p, v, ok := tr.Lookup(addr("10.0.0.5")); !ok || v != "v2" || p.String() != "10.0.0.0/24" {
+		t.Fatalf("got prefix=%v value=%q ok=%v, want 10.0.0.0/24=v2", p, v, ok)
+	}
+}
+
+func TestDeleteWithinTaggedOverlap(t *testing.T) {
+	tr := NewTree[string](32)
+	tr.Insert(prefix("10.0.0.0/24"), "outer")
+	tr.Insert(prefix("10.0.0.128/25"), "inner")
+
+	if !tr.Delete(prefix("10.0.0.128/25")) {
+		t.Fatal("expected Delete of the more specific block to succeed")
+	}
+	// Delete removes exactly the block named; it doesn't resurrect the
+	// broader "outer" tag that used to cover the same addresses.
+	if _, _, ok := tr.Lookup(addr("10.0.0.200")); ok {
+		t.Error("expected no match after deleting the only tag covering this address")
+	}
+	if p, v, ok := tr.Lookup(addr("10.0.0.1")); !ok || v != "outer" || p.String() != "10.0.0.0/25" {
+		t.Fatalf("got prefix=%v value=%q ok=%v, want 10.0.0.0/25=outer to be unaffected", p, v, ok)
+	}
+}
+
+func TestInsertRange(t *testing.T) {
+	tr := NewTree[struct{}](32)
+	tr.InsertRange(addr("192.168.2.1"), addr("192.168.2.5"), struct{}{})
+
+	var got []string
+	tr.CIDRs(func(e Entry[struct{}]) bool {
+		got = append(got, e.Prefix.String())
+		return true
+	})
+	want := []string{"192.168.2.1/32", "192.168.2.2/31", "192.168.2.4/31"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestInsertRangeCoveringWholeFamily(t *testing.T) {
+	// start == end == the all-ones top address of the family: rangeBlocks
+	// must stop after the final block instead of wrapping past it.
+	tests := []struct {
+		name  string
+		bits  int
+		start string
+		end   string
+		want  string
+	}{
+		{"ipv4", 32, "0.0.0.0", "255.255.255.255", "0.0.0.0/0"},
+		{"ipv6", 128, "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "::/0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTree[struct{}](tt.bits)
+			tr.InsertRange(addr(tt.start), addr(tt.end), struct{}{})
+
+			var got []string
+			tr.CIDRs(func(e Entry[struct{}]) bool {
+				got = append(got, e.Prefix.String())
+				return true
+			})
+			if len(got) != 1 || got[0] != tt.want {
+				t.Fatalf("got %v, want [%s]", got, tt.want)
+			}
+		})
+	}
+}
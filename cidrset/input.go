@@ -0,0 +1,292 @@
+package cidrset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Record is one address range read from an input source. For CIDR-aligned
+// input it's exactly the range covered by the prefix; RIR stats and ranges
+// given as "start-end" need not be CIDR-aligned at all.
+type Record struct {
+	Start, End netip.Addr
+}
+
+// Stats tallies why records were skipped during a parse, so a caller can
+// tell a source full of garbage from one full of e.g. inverted ranges.
+type Stats struct {
+	ParseErrors    int // syntactically malformed records
+	InvalidRanges  int // well-formed but semantically invalid (start > end, mixed families, ...)
+	LegacyAccepted int // addresses that only parsed because of legacy leniency (see ParseLinesLegacy)
+}
+
+// Parser reads Records from r and calls fn for each one successfully
+// parsed. Malformed individual records are counted in the returned Stats,
+// not fatal; only an I/O error reading r itself stops the parse.
+type Parser func(r io.Reader, fn func(Record)) (Stats, error)
+
+// ParseLines reads one record per line: a bare address, a CIDR ("a.b.c.d/n"
+// or "2001:db8::/32"), or an inclusive range ("a.b.c.d-a.b.c.e"), the
+// grammar emitted by tools like aggregate(1) and iprange. Blank lines are
+// skipped. Addresses are parsed strictly; see ParseLinesLegacy to accept
+// IPv4 octets with leading zeros.
+func ParseLines(r io.Reader, fn func(Record)) (Stats, error) {
+	return parseLines(r, fn, netip.ParseAddr)
+}
+
+// ParseLinesLegacy behaves like ParseLines but additionally accepts IPv4
+// addresses with leading-zero decimal octets, e.g. "192.168.001.001",
+// which net/netip (like net.ParseIP since Go 1.17) rejects. Stats.LegacyAccepted
+// counts how many addresses needed that leniency.
+func ParseLinesLegacy(r io.Reader, fn func(Record)) (Stats, error) {
+	var legacyUsed int
+	stats, err := parseLines(r, fn, func(s string) (netip.Addr, error) {
+		a, usedLegacy, err := parseAddrLegacy(s)
+		if usedLegacy {
+			legacyUsed++
+		}
+		return a, err
+	})
+	stats.LegacyAccepted = legacyUsed
+	return stats, err
+}
+
+func parseLines(r io.Reader, fn func(Record), parseAddr func(string) (netip.Addr, error)) (Stats, error) {
+	var stats Stats
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if start, end, ok := strings.Cut(line, "-"); ok {
+			s, err1 := parseAddr(strings.TrimSpace(start))
+			e, err2 := parseAddr(strings.TrimSpace(end))
+			if err1 != nil || err2 != nil {
+				stats.ParseErrors++
+				continue
+			}
+			if s.Is4() != e.Is4() || s.Compare(e) > 0 {
+				stats.InvalidRanges++
+				continue
+			}
+			fn(Record{s, e})
+			continue
+		}
+		if addrPart, bitsPart, ok := strings.Cut(line, "/"); ok {
+			addr, err := parseAddr(addrPart)
+			if err != nil {
+				stats.ParseErrors++
+				continue
+			}
+			bits, err := strconv.Atoi(bitsPart)
+			if err != nil {
+				stats.ParseErrors++
+				continue
+			}
+			p := netip.PrefixFrom(addr, bits)
+			if !p.IsValid() {
+				stats.InvalidRanges++
+				continue
+			}
+			s, e := prefixRange(p)
+			fn(Record{s, e})
+			continue
+		}
+		a, err := parseAddr(line)
+		if err != nil {
+			stats.ParseErrors++
+			continue
+		}
+		fn(Record{a, a})
+	}
+	return stats, scanner.Err()
+}
+
+// ParseRIRStats reads a RIR "delegated-stats" file (the format published by
+// ARIN/APNIC/RIPE/etc., e.g. "apnic|AU|ipv4|1.0.0.0|256|20110811|allocated"
+// or, for ipv6, "apnic|AU|ipv6|2001:db8::|32|20110811|allocated" where the
+// count field is a prefix length rather than an address count). Version,
+// summary ("cc" of "*") and non-ipv4/ipv6 (e.g. asn) lines are skipped
+// without counting against Stats, since they're a normal part of the format
+// rather than malformed input.
+func ParseRIRStats(r io.Reader, fn func(Record)) (Stats, error) {
+	var stats Stats
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "|")
+		if len(fields) < 7 || fields[1] == "*" || fields[3] == "*" {
+			continue
+		}
+		if fields[2] != "ipv4" && fields[2] != "ipv6" {
+			continue
+		}
+		start, err := netip.ParseAddr(fields[3])
+		if err != nil {
+			stats.ParseErrors++
+			continue
+		}
+		value, err := strconv.ParseUint(fields[4], 10, 32)
+		if err != nil {
+			stats.ParseErrors++
+			continue
+		}
+		switch fields[2] {
+		case "ipv4":
+			if value == 0 {
+				stats.InvalidRanges++
+				continue
+			}
+			offset := make([]byte, 4)
+			binary.BigEndian.PutUint32(offset, uint32(value-1))
+			endBytes := addBytes(start.AsSlice(), offset)
+			if cmpBytes(endBytes, start.AsSlice()) < 0 {
+				// start+offset overflowed past 255.255.255.255.
+				stats.InvalidRanges++
+				continue
+			}
+			fn(Record{start, addrFromBytes(endBytes)})
+		case "ipv6":
+			p := netip.PrefixFrom(start, int(value))
+			if !p.IsValid() {
+				stats.InvalidRanges++
+				continue
+			}
+			s, e := prefixRange(p)
+			fn(Record{s, e})
+		}
+	}
+	return stats, scanner.Err()
+}
+
+// ParseJSON reads a JSON array of strings, each either a CIDR
+// ("a.b.c.d/n") or an inclusive range ("a.b.c.d-a.b.c.e").
+func ParseJSON(r io.Reader, fn func(Record)) (Stats, error) {
+	var stats Stats
+	var items []string
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return stats, err
+	}
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if start, end, ok := strings.Cut(item, "-"); ok {
+			s, err1 := netip.ParseAddr(strings.TrimSpace(start))
+			e, err2 := netip.ParseAddr(strings.TrimSpace(end))
+			if err1 != nil || err2 != nil {
+				stats.ParseErrors++
+				continue
+			}
+			if s.Is4() != e.Is4() || s.Compare(e) > 0 {
+				stats.InvalidRanges++
+				continue
+			}
+			fn(Record{s, e})
+			continue
+		}
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			stats.ParseErrors++
+			continue
+		}
+		s, e := prefixRange(p)
+		fn(Record{s, e})
+	}
+	return stats, nil
+}
+
+// MRT RIB subtypes that carry a prefix we know how to read. Other TABLE_DUMP_V2
+// subtypes (notably PEER_INDEX_TABLE) are skipped wholesale.
+const (
+	mrtTypeTableDumpV2       = 13
+	mrtSubtypeRIBIPv4Unicast = 2
+	mrtSubtypeRIBIPv6Unicast = 4
+)
+
+// maxMRTRecordLen bounds the 32-bit on-disk record length from an MRT
+// header before it's trusted as an allocation size. It's far larger than
+// any real TABLE_DUMP_V2 record (a handful of bytes plus one RIB entry's
+// attributes) so it only rejects truncated/corrupt headers, not legitimate
+// dumps.
+const maxMRTRecordLen = 16 << 20
+
+// ParseMRT reads prefixes out of an MRT (RFC 6396) TABLE_DUMP_V2 dump, the
+// format BGP daemons (and the RouteViews/RIPE RIS archives) use to publish a
+// full RIB snapshot for aggregation. Only the RIB_IPV4_UNICAST and
+// RIB_IPV6_UNICAST entry subtypes are decoded; their attribute blocks are
+// skipped unread since only the prefix being announced is of interest here.
+// PEER_INDEX_TABLE records and any other MRT type are skipped. A truncated
+// or malformed record is a fatal error rather than a Stats tally, since
+// unlike the text formats there's no reliable way to resync mid-stream.
+func ParseMRT(r io.Reader, fn func(Record)) (Stats, error) {
+	var stats Stats
+	br := bufio.NewReader(r)
+	var hdr [12]byte
+	for {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if err == io.EOF {
+				return stats, nil
+			}
+			return stats, err
+		}
+		typ := binary.BigEndian.Uint16(hdr[4:6])
+		subtype := binary.BigEndian.Uint16(hdr[6:8])
+		length := binary.BigEndian.Uint32(hdr[8:12])
+		if length > maxMRTRecordLen {
+			return stats, fmt.Errorf("cidrset: MRT record length %d exceeds %d-byte limit", length, maxMRTRecordLen)
+		}
+
+		body := make([]byte, length)
+		if _, err := io.ReadFull(br, body); err != nil {
+			return stats, err
+		}
+		if typ != mrtTypeTableDumpV2 {
+			continue
+		}
+		if err := parseMRTTableDumpV2(body, subtype, fn); err != nil {
+			return stats, fmt.Errorf("cidrset: malformed MRT TABLE_DUMP_V2 record: %w", err)
+		}
+	}
+}
+
+func parseMRTTableDumpV2(body []byte, subtype uint16, fn func(Record)) error {
+	var bits int
+	switch subtype {
+	case mrtSubtypeRIBIPv4Unicast:
+		bits = 32
+	case mrtSubtypeRIBIPv6Unicast:
+		bits = 128
+	default:
+		return nil // PEER_INDEX_TABLE or another subtype we don't decode.
+	}
+	// sequence number(4) + prefix length(1) + prefix bytes + entry count(2).
+	if len(body) < 5 {
+		return io.ErrUnexpectedEOF
+	}
+	prefixLen := int(body[4])
+	if prefixLen > bits {
+		return fmt.Errorf("prefix length %d exceeds %d-bit address", prefixLen, bits)
+	}
+	prefixBytes := (prefixLen + 7) / 8
+	addrBuf := make([]byte, bits/8)
+	if 5+prefixBytes > len(body) {
+		return io.ErrUnexpectedEOF
+	}
+	copy(addrBuf, body[5:5+prefixBytes])
+	p := netip.PrefixFrom(addrFromBytes(addrBuf), prefixLen)
+	if !p.IsValid() {
+		return fmt.Errorf("invalid prefix %s", p)
+	}
+	s, e := prefixRange(p)
+	fn(Record{s, e})
+	return nil
+}
@@ -0,0 +1,135 @@
+package cidrset
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func collectRecords(t *testing.T, p Parser, input string) []Record {
+	t.Helper()
+	var got []Record
+	if _, err := p(strings.NewReader(input), func(r Record) { got = append(got, r) }); err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	return got
+}
+
+func TestParseLines(t *testing.T) {
+	got := collectRecords(t, ParseLines, "10.0.0.0/24\n\n10.0.1.1\n10.0.2.1-10.0.2.5\nnot an ip\n")
+	if len(got) != 3 {
+		t.Fatalf("got %d records, want 3: %v", len(got), got)
+	}
+	if got[0].Start.String() != "10.0.0.0" || got[0].End.String() != "10.0.0.255" {
+		t.Errorf("unexpected CIDR record: %+v", got[0])
+	}
+	if got[2].Start.String() != "10.0.2.1" || got[2].End.String() != "10.0.2.5" {
+		t.Errorf("unexpected range record: %+v", got[2])
+	}
+}
+
+func TestParseRIRStats(t *testing.T) {
+	input := "apnic|AU|ipv4|1.0.0.0|256|20110811|allocated\n" +
+		"apnic|AU|ipv6|2001:db8::|32|20110811|allocated\n" +
+		"apnic|*|ipv4|*|100|summary\n" +
+		"2|apnic|20220401|999999|20220401\n"
+	got := collectRecords(t, ParseRIRStats, input)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(got), got)
+	}
+	if got[0].Start.String() != "1.0.0.0" || got[0].End.String() != "1.0.0.255" {
+		t.Errorf("unexpected ipv4 record: %+v", got[0])
+	}
+	if got[1].Start.String() != "2001:db8::" || got[1].End.String() != "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff" {
+		t.Errorf("unexpected ipv6 record: %+v", got[1])
+	}
+}
+
+func TestParseRIRStatsRejectsIPv4CountOverflow(t *testing.T) {
+	// A count field large enough that start+count-1 overflows past
+	// 255.255.255.255 must be flagged as invalid rather than silently
+	// producing a wrapped End address.
+	input := "apnic|AU|ipv4|255.255.255.0|4294967295|20110811|allocated\n"
+	stats, err := ParseRIRStats(strings.NewReader(input), func(Record) {
+		t.Fatal("did not expect a record for an overflowing count")
+	})
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if stats.InvalidRanges != 1 {
+		t.Fatalf("got %+v, want InvalidRanges=1", stats)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	got := collectRecords(t, ParseJSON, `["10.0.0.0/24", "10.0.1.1-10.0.1.5"]`)
+	if len(got) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(got), got)
+	}
+}
+
+func TestParseLinesStrictRejectsLeadingZeros(t *testing.T) {
+	var got []Record
+	stats, err := ParseLines(strings.NewReader("192.168.001.001\n"), func(r Record) { got = append(got, r) })
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(got) != 0 || stats.ParseErrors != 1 {
+		t.Fatalf("got records=%v stats=%+v, want it rejected as a parse error", got, stats)
+	}
+}
+
+func TestParseLinesLegacyAcceptsLeadingZeros(t *testing.T) {
+	var got []Record
+	stats, err := ParseLinesLegacy(strings.NewReader("192.168.001.001\n10.0.0.1\n"), func(r Record) { got = append(got, r) })
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(got) != 2 || got[0].Start.String() != "192.168.1.1" {
+		t.Fatalf("got %+v, want 192.168.1.1 parsed decimally despite the leading zeros", got)
+	}
+	if stats.LegacyAccepted != 1 {
+		t.Errorf("got LegacyAccepted=%d, want 1 (only the leading-zero address needed the fallback)", stats.LegacyAccepted)
+	}
+}
+
+func TestParseMRT(t *testing.T) {
+	var body []byte
+	body = append(body, 0, 0, 0, 1) // sequence number
+	body = append(body, 24)         // prefix length
+	body = append(body, 10, 0, 0)   // prefix bytes, 3 for a /24
+	body = append(body, 0, 0)       // entry count (unread)
+
+	var hdr [12]byte
+	binary.BigEndian.PutUint16(hdr[4:6], mrtTypeTableDumpV2)
+	binary.BigEndian.PutUint16(hdr[6:8], mrtSubtypeRIBIPv4Unicast)
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(len(body)))
+
+	var buf bytes.Buffer
+	buf.Write(hdr[:])
+	buf.Write(body)
+
+	got := collectRecords(t, ParseMRT, buf.String())
+	if len(got) != 1 || got[0].Start.String() != "10.0.0.0" || got[0].End.String() != "10.0.0.255" {
+		t.Fatalf("got %+v, want a single 10.0.0.0/24 record", got)
+	}
+}
+
+func TestParseMRTRejectsOversizedRecordLength(t *testing.T) {
+	// A corrupt or truncated header claiming a huge record length must be
+	// rejected before it's trusted as an allocation size, rather than
+	// forcing a multi-gigabyte allocation ahead of the read that would
+	// otherwise fail cheaply.
+	var hdr [12]byte
+	binary.BigEndian.PutUint16(hdr[4:6], mrtTypeTableDumpV2)
+	binary.BigEndian.PutUint16(hdr[6:8], mrtSubtypeRIBIPv4Unicast)
+	binary.BigEndian.PutUint32(hdr[8:12], maxMRTRecordLen+1)
+
+	_, err := ParseMRT(bytes.NewReader(hdr[:]), func(Record) {
+		t.Fatal("did not expect a record from an oversized length header")
+	})
+	if err == nil {
+		t.Fatal("expected an error for an oversized MRT record length")
+	}
+}
@@ -0,0 +1,54 @@
+package cidrset
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// parseAddrLegacy parses s as an address, trying the strict net/netip
+// parser first and falling back to a lenient IPv4 dotted-quad parser that
+// accepts octets with leading zeros, decimally (e.g. "001" is 1, never
+// octal). net.ParseIP/netip.ParseAddr have rejected such octets since
+// Go 1.17, to close off the octal-vs-decimal ambiguity that made
+// leading-zero addresses a source of ACL-bypass bugs elsewhere; this
+// fallback exists only to ingest legacy datasets (old firewall exports,
+// historical blocklists) that still contain them, never to reintroduce
+// octal parsing. usedLegacy reports whether the fallback path was needed.
+func parseAddrLegacy(s string) (addr netip.Addr, usedLegacy bool, err error) {
+	if a, err := netip.ParseAddr(s); err == nil {
+		return a, false, nil
+	}
+	a, err := parseIPv4LeadingZeros(s)
+	if err != nil {
+		return netip.Addr{}, false, err
+	}
+	return a, true, nil
+}
+
+func parseIPv4LeadingZeros(s string) (netip.Addr, error) {
+	octets := strings.Split(s, ".")
+	if len(octets) != 4 {
+		return netip.Addr{}, fmt.Errorf("cidrset: %q is not a dotted-quad IPv4 address", s)
+	}
+	var b [4]byte
+	for i, o := range octets {
+		if len(o) == 0 || len(o) > 3 {
+			return netip.Addr{}, fmt.Errorf("cidrset: invalid octet %q in %q", o, s)
+		}
+		for _, c := range o {
+			if c < '0' || c > '9' {
+				return netip.Addr{}, fmt.Errorf("cidrset: invalid octet %q in %q", o, s)
+			}
+		}
+		// Parsed with an explicit base 10, so a leading zero never flips
+		// this into octal the way strconv.ParseUint(o, 0, 8) would.
+		v, err := strconv.ParseUint(o, 10, 8)
+		if err != nil {
+			return netip.Addr{}, fmt.Errorf("cidrset: invalid octet %q in %q", o, s)
+		}
+		b[i] = byte(v)
+	}
+	return netip.AddrFrom4(b), nil
+}
@@ -0,0 +1,55 @@
+package cidrset
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// Emitter writes prefixes to w, one call per output document (a line, a
+// JSON array, ...).
+type Emitter func(w io.Writer, prefixes []netip.Prefix) error
+
+// EmitCIDR writes one CIDR per line, e.g. "10.0.0.0/24".
+func EmitCIDR(w io.Writer, prefixes []netip.Prefix) error {
+	for _, p := range prefixes {
+		if _, err := fmt.Fprintln(w, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmitJSON writes prefixes as a JSON array of CIDR strings.
+func EmitJSON(w io.Writer, prefixes []netip.Prefix) error {
+	strs := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		strs[i] = p.String()
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(strs)
+}
+
+// EmitRanges writes one inclusive "start-end" range per line.
+func EmitRanges(w io.Writer, prefixes []netip.Prefix) error {
+	for _, p := range prefixes {
+		start, end := prefixRange(p)
+		if _, err := fmt.Fprintf(w, "%s-%s\n", start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EmitHex writes one "<hex base address>/<prefix length>" pair per line,
+// e.g. "0a000000/24" for 10.0.0.0/24.
+func EmitHex(w io.Writer, prefixes []netip.Prefix) error {
+	for _, p := range prefixes {
+		if _, err := fmt.Fprintf(w, "%x/%d\n", p.Masked().Addr().AsSlice(), p.Bits()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package cidrset
+
+import (
+	"bytes"
+	"net/netip"
+	"testing"
+)
+
+func TestEmitters(t *testing.T) {
+	prefixes := []netip.Prefix{prefix("10.0.0.0/24"), prefix("192.168.1.0/25")}
+
+	tests := []struct {
+		name    string
+		emit    Emitter
+		wantSub string
+	}{
+		{"cidr", EmitCIDR, "10.0.0.0/24\n"},
+		{"json", EmitJSON, `"10.0.0.0/24"`},
+		{"ranges", EmitRanges, "10.0.0.0-10.0.0.255\n"},
+		{"hex", EmitHex, "0a000000/24\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.emit(&buf, prefixes); err != nil {
+				t.Fatalf("emit error: %v", err)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte(tt.wantSub)) {
+				t.Errorf("output %q does not contain %q", buf.String(), tt.wantSub)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package cidrset
+
+import (
+	"net/netip"
+	"sort"
+)
+
+// Range is an inclusive span of addresses, always of a single family.
+type Range struct {
+	Start, End netip.Addr
+}
+
+// RangeSet holds a set of addresses as a sorted slice of merged, disjoint
+// ranges, searched with a binary search. Compared to Tree, lookups and set
+// algebra are cheaper (O(log n) vs O(bits)), but every insert dirties the
+// set and forces an O(n log n) re-sort on next use, so RangeSet suits
+// mostly-static rulesets built once and queried many times; Tree suits
+// rulesets that keep changing.
+//
+// Like Tree, a RangeSet only ever holds one address family.
+type RangeSet struct {
+	ranges []Range
+	dirty  bool
+}
+
+// NewRangeSet returns an empty RangeSet.
+func NewRangeSet() *RangeSet {
+	return &RangeSet{}
+}
+
+// InsertRange adds every address in [start, end] to the set.
+func (s *RangeSet) InsertRange(start, end netip.Addr) {
+	s.ranges = append(s.ranges, Range{start, end})
+	s.dirty = true
+}
+
+// InsertPrefix adds every address in prefix to the set.
+func (s *RangeSet) InsertPrefix(prefix netip.Prefix) {
+	start, end := prefixRange(prefix)
+	s.InsertRange(start, end)
+}
+
+// Contains reports whether a falls within the set.
+func (s *RangeSet) Contains(a netip.Addr) bool {
+	s.compact()
+	i := sort.Search(len(s.ranges), func(i int) bool { return a.Compare(s.ranges[i].End) <= 0 })
+	return i < len(s.ranges) && a.Compare(s.ranges[i].Start) >= 0
+}
+
+// ContainsRange reports whether every address in [start, end] falls within
+// a single range already in the set.
+func (s *RangeSet) ContainsRange(start, end netip.Addr) bool {
+	s.compact()
+	i := sort.Search(len(s.ranges), func(i int) bool { return start.Compare(s.ranges[i].End) <= 0 })
+	return i < len(s.ranges) && start.Compare(s.ranges[i].Start) >= 0 && end.Compare(s.ranges[i].End) <= 0
+}
+
+// Union returns a new RangeSet holding every address in s or other.
+func (s *RangeSet) Union(other *RangeSet) *RangeSet {
+	s.compact()
+	other.compact()
+	out := &RangeSet{dirty: true}
+	out.ranges = append(out.ranges, s.ranges...)
+	out.ranges = append(out.ranges, other.ranges...)
+	out.compact()
+	return out
+}
+
+// Intersect returns a new RangeSet holding every address in both s and
+// other.
+func (s *RangeSet) Intersect(other *RangeSet) *RangeSet {
+	s.compact()
+	other.compact()
+	out := &RangeSet{}
+	i, j := 0, 0
+	for i < len(s.ranges) && j < len(other.ranges) {
+		a, b := s.ranges[i], other.ranges[j]
+		lo, hi := maxAddr(a.Start, b.Start), minAddr(a.End, b.End)
+		if lo.Compare(hi) <= 0 {
+			out.ranges = append(out.ranges, Range{lo, hi})
+		}
+		if a.End.Compare(b.End) < 0 {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// Subtract returns a new RangeSet holding every address in s that is not
+// also in other.
+func (s *RangeSet) Subtract(other *RangeSet) *RangeSet {
+	s.compact()
+	other.compact()
+	out := &RangeSet{}
+	j := 0
+	for _, a := range s.ranges {
+		cur := a.Start
+		done := false
+		for j < len(other.ranges) && other.ranges[j].End.Compare(cur) < 0 {
+			j++
+		}
+		for k := j; !done && k < len(other.ranges) && other.ranges[k].Start.Compare(a.End) <= 0; k++ {
+			b := other.ranges[k]
+			if b.Start.Compare(cur) > 0 {
+				// b.Start > cur >= a.Start, so b.Start can't be the
+				// zero address and addrPrev always succeeds here.
+				prev, _ := addrPrev(b.Start)
+				out.ranges = append(out.ranges, Range{cur, prev})
+			}
+			if b.End.Compare(cur) >= 0 {
+				next, ok := addrNext(b.End)
+				if !ok {
+					done = true
+					break
+				}
+				cur = next
+			}
+			if cur.Compare(a.End) > 0 {
+				done = true
+			}
+		}
+		if !done && cur.Compare(a.End) <= 0 {
+			out.ranges = append(out.ranges, Range{cur, a.End})
+		}
+	}
+	return out
+}
+
+// ToCIDRs returns the minimal set of CIDRs covering every address in the
+// set, in ascending address order.
+func (s *RangeSet) ToCIDRs() []netip.Prefix {
+	s.compact()
+	var out []netip.Prefix
+	for _, r := range s.ranges {
+		rangeBlocks(r.Start.AsSlice(), r.End.AsSlice(), func(base []byte, prefixLen int) {
+			out = append(out, netip.PrefixFrom(addrFromBytes(base), prefixLen))
+		})
+	}
+	return out
+}
+
+// compact sorts and merges overlapping or adjacent ranges in place.
+func (s *RangeSet) compact() {
+	if !s.dirty {
+		return
+	}
+	sort.Slice(s.ranges, func(i, j int) bool {
+		return s.ranges[i].Start.Compare(s.ranges[j].Start) < 0
+	})
+	merged := s.ranges[:0]
+	for _, r := range s.ranges {
+		// Merge r into the last accumulated range if it overlaps or is
+		// exactly adjacent to it (prev(r.Start) == merged[n-1].End).
+		adjacent := false
+		if prev, ok := addrPrev(r.Start); ok {
+			adjacent = len(merged) > 0 && prev.Compare(merged[len(merged)-1].End) == 0
+		}
+		if n := len(merged); n > 0 && (r.Start.Compare(merged[n-1].End) <= 0 || adjacent) {
+			if r.End.Compare(merged[n-1].End) > 0 {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	s.ranges = merged
+	s.dirty = false
+}
+
+func prefixRange(p netip.Prefix) (start, end netip.Addr) {
+	p = p.Masked()
+	start = p.Addr()
+	b := start.AsSlice()
+	for i := 0; i < len(b)*8-p.Bits(); i++ {
+		b = setBit(b, i)
+	}
+	return start, addrFromBytes(b)
+}
+
+// addrNext returns a+1 and true, or the zero Addr and false if a is already
+// the top address of its family (no successor exists).
+func addrNext(a netip.Addr) (netip.Addr, bool) {
+	b := a.AsSlice()
+	allOnes := true
+	for _, x := range b {
+		if x != 0xff {
+			allOnes = false
+			break
+		}
+	}
+	if allOnes {
+		return netip.Addr{}, false
+	}
+	one := make([]byte, len(b))
+	one[len(one)-1] = 1
+	return addrFromBytes(addBytes(b, one)), true
+}
+
+// addrPrev returns a-1 and true, or the zero Addr and false if a is already
+// the bottom address of its family (no predecessor exists).
+func addrPrev(a netip.Addr) (netip.Addr, bool) {
+	b := a.AsSlice()
+	allZero := true
+	for _, x := range b {
+		if x != 0 {
+			allZero = false
+			break
+		}
+	}
+	if allZero {
+		return netip.Addr{}, false
+	}
+	return addrFromBytes(decBytes(b)), true
+}
+
+func maxAddr(a, b netip.Addr) netip.Addr {
+	if a.Compare(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+func minAddr(a, b netip.Addr) netip.Addr {
+	if a.Compare(b) <= 0 {
+		return a
+	}
+	return b
+}
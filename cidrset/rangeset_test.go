@@ -0,0 +1,107 @@
+package cidrset
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func toStrings(prefixes []netip.Prefix) []string {
+	out := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		out[i] = p.String()
+	}
+	return out
+}
+
+func TestRangeSetContains(t *testing.T) {
+	s := NewRangeSet()
+	s.InsertPrefix(prefix("10.0.0.0/24"))
+
+	if !s.Contains(addr("10.0.0.17")) {
+		t.Error("expected 10.0.0.17 to be contained in 10.0.0.0/24")
+	}
+	if s.Contains(addr("10.0.1.1")) {
+		t.Error("did not expect 10.0.1.1 to be contained in 10.0.0.0/24")
+	}
+}
+
+func TestRangeSetMergesAdjacent(t *testing.T) {
+	s := NewRangeSet()
+	s.InsertPrefix(prefix("192.168.0.0/24"))
+	s.InsertPrefix(prefix("192.168.1.0/24"))
+
+	got := toStrings(s.ToCIDRs())
+	want := []string{"192.168.0.0/23"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRangeSetContainsRange(t *testing.T) {
+	s := NewRangeSet()
+	s.InsertPrefix(prefix("10.0.0.0/24"))
+
+	if !s.ContainsRange(addr("10.0.0.10"), addr("10.0.0.20")) {
+		t.Error("expected [10.0.0.10, 10.0.0.20] to be contained in 10.0.0.0/24")
+	}
+	if s.ContainsRange(addr("10.0.0.250"), addr("10.0.1.5")) {
+		t.Error("did not expect a range crossing out of 10.0.0.0/24 to be contained")
+	}
+}
+
+func TestRangeSetUnion(t *testing.T) {
+	a := NewRangeSet()
+	a.InsertPrefix(prefix("10.0.0.0/24"))
+	b := NewRangeSet()
+	b.InsertPrefix(prefix("10.0.1.0/24"))
+
+	got := toStrings(a.Union(b).ToCIDRs())
+	want := []string{"10.0.0.0/23"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRangeSetIntersect(t *testing.T) {
+	a := NewRangeSet()
+	a.InsertRange(addr("10.0.0.0"), addr("10.0.0.200"))
+	b := NewRangeSet()
+	b.InsertRange(addr("10.0.0.100"), addr("10.0.0.255"))
+
+	got := a.Intersect(b)
+	if !got.Contains(addr("10.0.0.150")) {
+		t.Error("expected the overlap to contain 10.0.0.150")
+	}
+	if got.Contains(addr("10.0.0.50")) || got.Contains(addr("10.0.0.250")) {
+		t.Error("intersection should not extend past the overlap")
+	}
+}
+
+func TestRangeSetSubtract(t *testing.T) {
+	a := NewRangeSet()
+	a.InsertPrefix(prefix("10.0.0.0/24"))
+	b := NewRangeSet()
+	b.InsertRange(addr("10.0.0.64"), addr("10.0.0.127"))
+
+	got := a.Subtract(b)
+	if got.Contains(addr("10.0.0.100")) {
+		t.Error("did not expect the subtracted range to remain")
+	}
+	if !got.Contains(addr("10.0.0.1")) || !got.Contains(addr("10.0.0.254")) {
+		t.Error("expected addresses outside the subtracted range to remain")
+	}
+}
+
+func TestRangeSetToCIDRsCoveringWholeFamily(t *testing.T) {
+	// A range spanning the whole address space, including the family's
+	// all-ones top address, must produce a single /0 rather than hanging.
+	s := NewRangeSet()
+	s.InsertRange(addr("0.0.0.0"), addr("255.255.255.255"))
+
+	got := toStrings(s.ToCIDRs())
+	want := []string{"0.0.0.0/0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,84 @@
+package cidrset
+
+import "net/netip"
+
+// StreamAggregate consumes ranges from in and writes the minimal CIDR cover
+// to out, closing out once in is closed. Unlike Insert/InsertRange, it never
+// holds the whole trie in memory: at most O(bits) pending blocks are held at
+// any time, since addresses already committed to out can't be revisited.
+//
+// in MUST deliver ranges in non-decreasing, non-overlapping order by Start
+// (sort the input first, or require it pre-sorted, e.g. with the CLI's
+// -sorted flag) — StreamAggregate does not buffer enough to correct for
+// out-of-order input, so a range that arrives behind where aggregation has
+// already committed output is silently lost.
+func (t *Tree[V]) StreamAggregate(in <-chan Range, out chan<- netip.Prefix) {
+	defer close(out)
+
+	type block struct {
+		base      []byte
+		prefixLen int
+	}
+
+	var stack []block
+	bits := t.bits
+
+	// push merges b into the pending stack, cascading as far up as the
+	// data seen so far allows: it keeps popping and combining with the
+	// current top as long as b is exactly that top's buddy. Everything
+	// below the surviving top is left in place, since a block that
+	// arrives later can still complete a merge one or more levels
+	// higher up (see the gap check in the main loop for when that's no
+	// longer possible).
+	push := func(b block) {
+		for len(stack) > 0 {
+			top := stack[len(stack)-1]
+			if top.prefixLen != b.prefixLen || b.prefixLen == 0 {
+				break
+			}
+			parentLen := b.prefixLen - 1
+			bitIdx := bits - 1 - parentLen
+			if getBit(b.base, bitIdx) != 1 {
+				break // b isn't the "1" child of a parent at this depth
+			}
+			parentBase := append([]byte(nil), b.base...)
+			clearBit(parentBase, bitIdx)
+			if cmpBytes(parentBase, top.base) != 0 {
+				break // b and top aren't buddies
+			}
+			b = block{base: top.base, prefixLen: parentLen}
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, b)
+	}
+
+	flush := func() {
+		for _, b := range stack {
+			out <- netip.PrefixFrom(addrFromBytes(b.base), b.prefixLen)
+		}
+		stack = nil
+	}
+
+	var afterLast []byte // address immediately following the last range's End
+	for r := range in {
+		// A gap between this range's Start and where the last one ended
+		// means nothing still pending can ever be completed by future
+		// input, so it's safe (and necessary, to keep memory bounded) to
+		// flush everything before starting on the new range.
+		if afterLast != nil && cmpBytes(afterLast, r.Start.AsSlice()) != 0 {
+			flush()
+		}
+		rangeBlocks(r.Start.AsSlice(), r.End.AsSlice(), func(base []byte, prefixLen int) {
+			push(block{base: append([]byte(nil), base...), prefixLen: prefixLen})
+		})
+		one := make([]byte, len(r.End.AsSlice()))
+		one[len(one)-1] = 1
+		afterLast = addBytes(r.End.AsSlice(), one)
+	}
+	flush()
+}
+
+func clearBit(a []byte, bit int) {
+	byteIdx := len(a) - 1 - bit/8
+	a[byteIdx] &^= 1 << uint(bit%8)
+}
@@ -0,0 +1,99 @@
+package cidrset
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func runStreamAggregate(t *testing.T, bits int, ranges []Range) []string {
+	t.Helper()
+	tr := NewTree[struct{}](bits)
+	in := make(chan Range)
+	out := make(chan netip.Prefix)
+	go tr.StreamAggregate(in, out)
+	go func() {
+		for _, r := range ranges {
+			in <- r
+		}
+		close(in)
+	}()
+	var got []string
+	for p := range out {
+		got = append(got, p.String())
+	}
+	return got
+}
+
+func TestStreamAggregateCoalescesAdjacent(t *testing.T) {
+	got := runStreamAggregate(t, 32, []Range{
+		{addr("192.168.0.0"), addr("192.168.0.255")},
+		{addr("192.168.1.0"), addr("192.168.1.255")},
+	})
+	want := []string{"192.168.0.0/23"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestStreamAggregateDisjointBlocks(t *testing.T) {
+	got := runStreamAggregate(t, 32, []Range{
+		{addr("10.0.0.1"), addr("10.0.0.1")},
+		{addr("10.0.2.0"), addr("10.0.2.255")},
+	})
+	want := []string{"10.0.0.1/32", "10.0.2.0/24"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestStreamAggregateMatchesInsertRange(t *testing.T) {
+	ranges := []Range{
+		{addr("192.168.2.1"), addr("192.168.2.5")},
+		{addr("192.168.3.0"), addr("192.168.3.255")},
+		{addr("192.168.4.0"), addr("192.168.5.255")},
+	}
+
+	tr := NewTree[struct{}](32)
+	for _, r := range ranges {
+		tr.InsertRange(r.Start, r.End, struct{}{})
+	}
+	var want []string
+	tr.CIDRs(func(e Entry[struct{}]) bool {
+		want = append(want, e.Prefix.String())
+		return true
+	})
+
+	got := runStreamAggregate(t, 32, ranges)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v (from a non-streaming Tree over the same input)", got, want)
+	}
+}
+
+func TestStreamAggregateCascadesAcrossMultipleLevels(t *testing.T) {
+	// Four ascending, contiguous /26s that exactly tile a /24 must collapse
+	// all the way up to that /24, not stop at the first /25 that happens
+	// to be complete before the rest of the cascade arrives.
+	got := runStreamAggregate(t, 32, []Range{
+		{addr("10.0.0.0"), addr("10.0.0.63")},
+		{addr("10.0.0.64"), addr("10.0.0.127")},
+		{addr("10.0.0.128"), addr("10.0.0.191")},
+		{addr("10.0.0.192"), addr("10.0.0.255")},
+	})
+	want := []string{"10.0.0.0/24"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestStreamAggregateCoveringWholeFamily(t *testing.T) {
+	// A range ending at the family's all-ones top address must not hang
+	// rangeBlocks; it should stream out a single /0 and close.
+	got := runStreamAggregate(t, 32, []Range{
+		{addr("0.0.0.0"), addr("255.255.255.255")},
+	})
+	want := []string{"0.0.0.0/0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}